@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/aws-sdk-go-v2/service/inspector2/types"
+)
+
+// candidateImage is an ECR image under consideration for pruning or
+// quarantining, annotated with the data the action thresholds are judged
+// against.
+type candidateImage struct {
+	Repository  string
+	Digest      string
+	Tags        []string
+	PushedAt    time.Time
+	MaxSeverity types.Severity
+}
+
+func (c candidateImage) hasExcludedTag(excludeTags map[string]bool) bool {
+	for _, t := range c.Tags {
+		if excludeTags[t] {
+			return true
+		}
+	}
+	return false
+}
+
+// runLifecycleCommand implements the `inspector prune` and `inspector
+// quarantine` subcommands: list ECR images, find the ones whose findings
+// exceed a severity threshold (or that are older than --max-age), and
+// either delete or re-tag them, always keeping the newest --keep clean
+// images per repository and anything matching --exclude-tag.
+func runLifecycleCommand(action string, args []string) {
+	fs := flag.NewFlagSet("inspector "+action, flag.ExitOnError)
+	profile := fs.String("p", "", "Name of AWS profile")
+	repo := fs.String("repo", "", "ECR repository name to act on (required)")
+	minSeverity := fs.String("min-severity", "CRITICAL", "Images with a finding at or above this severity are candidates for action")
+	maxAge := fs.Duration("max-age", 0, "Images older than this are also candidates for action, e.g. 720h (0 disables the age check)")
+	keep := fs.Int("keep", 1, "Always keep the newest N images per repository that have no qualifying findings")
+	var excludeTagValues []string
+	fs.Var(repeatedFlag{&excludeTagValues}, "exclude-tag", "Tag that protects an image from action, e.g. latest (repeatable)")
+	yes := fs.Bool("yes", false, "Required to actually perform the action; without it, nothing is changed")
+	dryRun := fs.Bool("dry-run", false, "Print exactly which image digests would be affected and exit")
+	fs.Parse(args)
+
+	if *profile == "" {
+		log.Fatal("Please provide an AWS profile name with -p")
+	}
+	if *repo == "" {
+		log.Fatal("Please provide a repository name with -repo")
+	}
+	if !*dryRun && !*yes {
+		log.Fatal("Refusing to act without -yes (or pass -dry-run to preview)")
+	}
+
+	if _, ok := severityOrder[strings.ToUpper(*minSeverity)]; !ok {
+		log.Fatalf("unknown -min-severity %q", *minSeverity)
+	}
+
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithSharedConfigProfile(*profile))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	images, err := describeImages(ctx, cfg, *repo)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	spec := &FilterSpec{IncludeRepo: []string{"^" + regexp.QuoteMeta(*repo) + "$"}}
+	if err := spec.compile(); err != nil {
+		log.Fatal(err)
+	}
+	findings, err := fetchFindings(ctx, cfg, spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	severityByDigest := maxSeverityByDigest(findings)
+	for i := range images {
+		images[i].MaxSeverity = severityByDigest[images[i].Digest]
+	}
+
+	excludeTags := make(map[string]bool, len(excludeTagValues))
+	for _, t := range excludeTagValues {
+		excludeTags[t] = true
+	}
+
+	candidates := selectCandidates(images, *minSeverity, *maxAge, *keep, excludeTags, time.Now())
+	if len(candidates) == 0 {
+		fmt.Println("No images meet the action thresholds.")
+		return
+	}
+
+	fmt.Printf("%s would affect %d image(s) in %s:\n", action, len(candidates), *repo)
+	for _, c := range candidates {
+		fmt.Printf("  %s tags=%v severity=%s pushed=%s\n", c.Digest, c.Tags, c.MaxSeverity, c.PushedAt.Format(time.RFC3339))
+	}
+	if *dryRun {
+		return
+	}
+
+	client := ecr.NewFromConfig(cfg)
+	switch action {
+	case "prune":
+		err = pruneImages(ctx, client, *repo, candidates)
+	case "quarantine":
+		err = quarantineImages(ctx, client, *repo, candidates, time.Now())
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func describeImages(ctx context.Context, cfg aws.Config, repo string) ([]candidateImage, error) {
+	client := ecr.NewFromConfig(cfg)
+	paginator := ecr.NewDescribeImagesPaginator(client, &ecr.DescribeImagesInput{RepositoryName: aws.String(repo)})
+
+	var images []candidateImage
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range page.ImageDetails {
+			images = append(images, candidateImage{
+				Repository: repo,
+				Digest:     aws.ToString(d.ImageDigest),
+				Tags:       d.ImageTags,
+				PushedAt:   aws.ToTime(d.ImagePushedAt),
+			})
+		}
+	}
+	return images, nil
+}
+
+// maxSeverityByDigest maps each image digest to the highest severity among
+// its findings.
+func maxSeverityByDigest(findings []types.Finding) map[string]types.Severity {
+	highest := make(map[string]types.Severity)
+	for _, f := range findings {
+		if len(f.Resources) != 1 || f.Resources[0].Details.AwsEcrContainerImage == nil {
+			continue
+		}
+		digest := aws.ToString(f.Resources[0].Details.AwsEcrContainerImage.ImageHash)
+		if digest == "" {
+			continue
+		}
+		if current, ok := highest[digest]; !ok || severityOrder[string(f.Severity)] > severityOrder[string(current)] {
+			highest[digest] = f.Severity
+		}
+	}
+	return highest
+}
+
+// selectCandidates decides which images exceed the severity/age thresholds,
+// then protects the newest `keep` clean images and anything with an
+// excluded tag.
+func selectCandidates(images []candidateImage, minSeverity string, maxAge time.Duration, keep int, excludeTags map[string]bool, now time.Time) []candidateImage {
+	threshold := severityOrder[strings.ToUpper(minSeverity)]
+
+	isVulnerable := func(c candidateImage) bool {
+		return severityOrder[string(c.MaxSeverity)] >= threshold && c.MaxSeverity != ""
+	}
+	isOld := func(c candidateImage) bool {
+		return maxAge > 0 && now.Sub(c.PushedAt) > maxAge
+	}
+
+	var clean []candidateImage
+	for _, img := range images {
+		if !isVulnerable(img) {
+			clean = append(clean, img)
+		}
+	}
+	sort.Slice(clean, func(i, j int) bool { return clean[i].PushedAt.After(clean[j].PushedAt) })
+	protected := make(map[string]bool, keep)
+	for i := 0; i < len(clean) && i < keep; i++ {
+		protected[clean[i].Digest] = true
+	}
+
+	var candidates []candidateImage
+	for _, img := range images {
+		if protected[img.Digest] || img.hasExcludedTag(excludeTags) {
+			continue
+		}
+		if isVulnerable(img) || isOld(img) {
+			candidates = append(candidates, img)
+		}
+	}
+	return candidates
+}
+
+func pruneImages(ctx context.Context, client *ecr.Client, repo string, candidates []candidateImage) error {
+	ids := make([]ecrtypes.ImageIdentifier, len(candidates))
+	for i, c := range candidates {
+		ids[i] = ecrtypes.ImageIdentifier{ImageDigest: aws.String(c.Digest)}
+	}
+	out, err := client.BatchDeleteImage(ctx, &ecr.BatchDeleteImageInput{
+		RepositoryName: aws.String(repo),
+		ImageIds:       ids,
+	})
+	if err != nil {
+		return err
+	}
+	for _, failure := range out.Failures {
+		fmt.Printf("failed to delete %s: %s\n", aws.ToString(failure.ImageId.ImageDigest), aws.ToString(failure.FailureReason))
+	}
+	fmt.Printf("Deleted %d image(s) from %s.\n", len(out.ImageIds), repo)
+	return nil
+}
+
+// quarantineImages re-tags each candidate with a quarantined-<date> tag by
+// fetching its manifest and pushing it back under the new tag (ECR has no
+// direct "copy tag" API), then removes the image's original tags so it is
+// no longer reachable under, e.g., "latest".
+func quarantineImages(ctx context.Context, client *ecr.Client, repo string, candidates []candidateImage, now time.Time) error {
+	quarantineTag := fmt.Sprintf("quarantined-%s", now.Format("2006-01-02"))
+	for _, c := range candidates {
+		getOut, err := client.BatchGetImage(ctx, &ecr.BatchGetImageInput{
+			RepositoryName: aws.String(repo),
+			ImageIds:       []ecrtypes.ImageIdentifier{{ImageDigest: aws.String(c.Digest)}},
+		})
+		if err != nil {
+			return fmt.Errorf("fetching manifest for %s: %w", c.Digest, err)
+		}
+		if len(getOut.Images) == 0 {
+			return fmt.Errorf("no manifest returned for %s", c.Digest)
+		}
+		image := getOut.Images[0]
+		_, err = client.PutImage(ctx, &ecr.PutImageInput{
+			RepositoryName:         aws.String(repo),
+			ImageManifest:          image.ImageManifest,
+			ImageManifestMediaType: image.ImageManifestMediaType,
+			ImageTag:               aws.String(quarantineTag),
+		})
+		if err != nil {
+			return fmt.Errorf("tagging %s as %s: %w", c.Digest, quarantineTag, err)
+		}
+		fmt.Printf("Tagged %s as %s\n", c.Digest, quarantineTag)
+
+		if err := untagImage(ctx, client, repo, c.Tags); err != nil {
+			return fmt.Errorf("removing original tags from %s: %w", c.Digest, err)
+		}
+	}
+	return nil
+}
+
+// untagImage removes an image's previous tags so it is no longer reachable
+// under any tag but the new quarantine one; BatchDeleteImage deletes just
+// the tag (and, per tag, the underlying image once no tags reference it),
+// which is how ECR requires a "retag" to be finished.
+func untagImage(ctx context.Context, client *ecr.Client, repo string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	ids := make([]ecrtypes.ImageIdentifier, len(tags))
+	for i, tag := range tags {
+		ids[i] = ecrtypes.ImageIdentifier{ImageTag: aws.String(tag)}
+	}
+	out, err := client.BatchDeleteImage(ctx, &ecr.BatchDeleteImageInput{
+		RepositoryName: aws.String(repo),
+		ImageIds:       ids,
+	})
+	if err != nil {
+		return err
+	}
+	for _, failure := range out.Failures {
+		fmt.Printf("failed to remove tag %s: %s\n", aws.ToString(failure.ImageId.ImageTag), aws.ToString(failure.FailureReason))
+	}
+	return nil
+}
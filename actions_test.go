@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/inspector2/types"
+)
+
+func TestSelectCandidates(t *testing.T) {
+	now := time.Now()
+	images := []candidateImage{
+		{Digest: "critical", MaxSeverity: types.SeverityCritical, PushedAt: now},
+		{Digest: "old-clean", MaxSeverity: "", PushedAt: now.Add(-800 * time.Hour)},
+		{Digest: "newest-clean", MaxSeverity: "", PushedAt: now},
+		{Digest: "excluded-tag", MaxSeverity: types.SeverityCritical, Tags: []string{"latest"}, PushedAt: now},
+	}
+
+	candidates := selectCandidates(images, "CRITICAL", 720*time.Hour, 1, map[string]bool{"latest": true}, now)
+
+	byDigest := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		byDigest[c.Digest] = true
+	}
+
+	if !byDigest["critical"] {
+		t.Error("expected the critical-severity image to be a candidate")
+	}
+	if !byDigest["old-clean"] {
+		t.Error("expected the old clean image to be a candidate")
+	}
+	if byDigest["newest-clean"] {
+		t.Error("newest clean image should be protected by -keep")
+	}
+	if byDigest["excluded-tag"] {
+		t.Error("image with an excluded tag should never be a candidate")
+	}
+}
+
+func TestSelectCandidatesCleanImagesWithoutMaxAge(t *testing.T) {
+	now := time.Now()
+	images := []candidateImage{
+		{Digest: "clean-1", MaxSeverity: "", PushedAt: now},
+		{Digest: "clean-2", MaxSeverity: "", PushedAt: now.Add(-time.Hour)},
+	}
+
+	candidates := selectCandidates(images, "CRITICAL", 0, 1, nil, now)
+	if len(candidates) != 0 {
+		t.Errorf("clean images with no age threshold should never be candidates, got %v", candidates)
+	}
+}
+
+func TestHasExcludedTag(t *testing.T) {
+	img := candidateImage{Tags: []string{"v1", "latest"}}
+	if !img.hasExcludedTag(map[string]bool{"latest": true}) {
+		t.Error("expected latest tag to be excluded")
+	}
+	if img.hasExcludedTag(map[string]bool{"stable": true}) {
+		t.Error("did not expect a match against an unrelated tag")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/inspector2"
+	"github.com/olekukonko/tablewriter"
+)
+
+// CoverageGap identifies an ECR repository with no matching entry in
+// Inspector2's ListCoverage results, i.e. a repository Inspector is not
+// scanning.
+type CoverageGap struct {
+	Account    string
+	Region     string
+	Repository string
+}
+
+// findCoverageGaps diffs every ECR repository in the account/region against
+// the repositories Inspector2 reports coverage for via ListCoverage.
+func findCoverageGaps(ctx context.Context, cfg aws.Config, account string) ([]CoverageGap, error) {
+	repos, err := allEcrRepositories(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	covered, err := coveredRepositories(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var gaps []CoverageGap
+	for _, repo := range repos {
+		if covered[repo] {
+			continue
+		}
+		gaps = append(gaps, CoverageGap{Account: account, Region: cfg.Region, Repository: repo})
+	}
+	return gaps, nil
+}
+
+func allEcrRepositories(ctx context.Context, cfg aws.Config) ([]string, error) {
+	client := ecr.NewFromConfig(cfg)
+	paginator := ecr.NewDescribeRepositoriesPaginator(client, &ecr.DescribeRepositoriesInput{})
+
+	var repos []string
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range page.Repositories {
+			repos = append(repos, aws.ToString(r.RepositoryName))
+		}
+	}
+	return repos, nil
+}
+
+func coveredRepositories(ctx context.Context, cfg aws.Config) (map[string]bool, error) {
+	client := inspector2.NewFromConfig(cfg)
+	paginator := inspector2.NewListCoveragePaginator(client, &inspector2.ListCoverageInput{})
+
+	covered := make(map[string]bool)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range page.CoveredResources {
+			if resource.ResourceMetadata == nil || resource.ResourceMetadata.EcrRepository == nil {
+				continue
+			}
+			covered[aws.ToString(resource.ResourceMetadata.EcrRepository.Name)] = true
+		}
+	}
+	return covered, nil
+}
+
+// scanCoverageGaps runs findCoverageGaps across every target, collecting
+// per-target errors the same way scanAccounts does for findings.
+func scanCoverageGaps(ctx context.Context, targets []scanTarget) ([]CoverageGap, []accountError) {
+	var gaps []CoverageGap
+	var errs []accountError
+	for _, target := range targets {
+		cfg, err := loadConfig(ctx, target)
+		if err != nil {
+			errs = append(errs, accountError{Target: target, Err: err})
+			continue
+		}
+		account, err := accountID(ctx, cfg)
+		if err != nil {
+			errs = append(errs, accountError{Target: target, Err: err})
+			continue
+		}
+		targetGaps, err := findCoverageGaps(ctx, cfg, account)
+		if err != nil {
+			errs = append(errs, accountError{Target: target, Err: err})
+			continue
+		}
+		gaps = append(gaps, targetGaps...)
+	}
+	return gaps, errs
+}
+
+// renderCoverageGaps prints gaps as a table, sorted the same way as the
+// findings summary, so the two can be read side by side.
+func renderCoverageGaps(w io.Writer, gaps []CoverageGap) error {
+	sort.Slice(gaps, func(i, j int) bool {
+		if gaps[i].Account != gaps[j].Account {
+			return gaps[i].Account < gaps[j].Account
+		}
+		if gaps[i].Region != gaps[j].Region {
+			return gaps[i].Region < gaps[j].Region
+		}
+		return gaps[i].Repository < gaps[j].Repository
+	})
+
+	fmt.Fprintln(w, "\nCoverage gaps (repositories Inspector is not scanning):")
+	table := tablewriter.NewWriter(w)
+	table.Header([]string{"Account", "Region", "Repository"})
+	for _, g := range gaps {
+		table.Append([]any{g.Account, g.Region, g.Repository})
+	}
+	return table.Render()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/inspector2"
+	"github.com/aws/aws-sdk-go-v2/service/inspector2/types"
+)
+
+// batchGetFindingDetailsLimit is the maximum number of finding ARNs the
+// BatchGetFindingDetails API accepts per call.
+const batchGetFindingDetailsLimit = 100
+
+// findingDetail is the subset of BatchGetFindingDetails data this tool
+// surfaces: EPSS exploit-prediction score, whether exploitation has been
+// observed, and CISA Known Exploited Vulnerabilities status.
+type findingDetail struct {
+	EPSSScore       float64
+	ExploitObserved bool
+	CisaKev         bool
+}
+
+// enrichFindingDetails calls BatchGetFindingDetails for every finding ARN,
+// batching requests to stay within the API limit, and returns the results
+// keyed by finding ARN.
+func enrichFindingDetails(ctx context.Context, cfg aws.Config, findings []types.Finding) (map[string]findingDetail, error) {
+	var arns []string
+	for _, f := range findings {
+		if f.FindingArn != nil {
+			arns = append(arns, *f.FindingArn)
+		}
+	}
+
+	client := inspector2.NewFromConfig(cfg)
+	details := make(map[string]findingDetail, len(arns))
+	for start := 0; start < len(arns); start += batchGetFindingDetailsLimit {
+		end := start + batchGetFindingDetailsLimit
+		if end > len(arns) {
+			end = len(arns)
+		}
+		out, err := client.BatchGetFindingDetails(ctx, &inspector2.BatchGetFindingDetailsInput{
+			FindingArns: arns[start:end],
+		})
+		if err != nil {
+			return details, err
+		}
+		for _, fd := range out.FindingDetails {
+			if fd.FindingArn == nil {
+				continue
+			}
+			detail := findingDetail{}
+			if fd.EpssScore != nil {
+				detail.EPSSScore = *fd.EpssScore
+			}
+			detail.ExploitObserved = fd.ExploitObserved != nil
+			detail.CisaKev = fd.CisaData != nil
+			details[*fd.FindingArn] = detail
+		}
+	}
+	return details, nil
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/inspector2/types"
+	"gopkg.in/yaml.v3"
+)
+
+// severityOrder ranks severities from lowest to highest so a --min-severity
+// floor can be applied with a simple integer comparison.
+var severityOrder = map[string]int{
+	"INFORMATIONAL": 0,
+	"LOW":           1,
+	"MEDIUM":        2,
+	"HIGH":          3,
+	"CRITICAL":      4,
+}
+
+// FilterSpec is the structured filter DSL used to narrow down findings. It
+// covers everything the Inspector2 API can express server-side (CVE IDs, fix
+// availability, severity floor) as well as the regex/glob matching the API has no
+// concept of, which is applied client-side once the findings are fetched.
+type FilterSpec struct {
+	IncludeRepo     []string `json:"includeRepo,omitempty" yaml:"includeRepo,omitempty"`
+	ExcludeRepo     []string `json:"excludeRepo,omitempty" yaml:"excludeRepo,omitempty"`
+	IncludeTag      []string `json:"includeTag,omitempty" yaml:"includeTag,omitempty"`
+	ExcludeTag      []string `json:"excludeTag,omitempty" yaml:"excludeTag,omitempty"`
+	MinSeverity     string   `json:"minSeverity,omitempty" yaml:"minSeverity,omitempty"`
+	IncludeCVE      []string `json:"includeCVE,omitempty" yaml:"includeCVE,omitempty"`
+	ExcludeCVE      []string `json:"excludeCVE,omitempty" yaml:"excludeCVE,omitempty"`
+	FixAvailable    *bool    `json:"fixAvailable,omitempty" yaml:"fixAvailable,omitempty"`
+	ExcludeInstance []string `json:"excludeInstance,omitempty" yaml:"excludeInstance,omitempty"`
+
+	includeRepoRe []*regexp.Regexp
+	excludeRepoRe []*regexp.Regexp
+	includeTagRe  []*regexp.Regexp
+	excludeTagRe  []*regexp.Regexp
+}
+
+// loadFilterFile reads a FilterSpec from a JSON or YAML file, selected by
+// extension, so filter sets can be checked into a repo and reused across
+// runs.
+func loadFilterFile(path string) (*FilterSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec := &FilterSpec{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, spec)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, spec)
+	default:
+		return nil, fmt.Errorf("unsupported filter file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing filter file %s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// compile pre-parses every regex pattern in the spec so they are not
+// recompiled once per finding.
+func (s *FilterSpec) compile() error {
+	var err error
+	if s.includeRepoRe, err = compilePatterns(s.IncludeRepo); err != nil {
+		return fmt.Errorf("includeRepo: %w", err)
+	}
+	if s.excludeRepoRe, err = compilePatterns(s.ExcludeRepo); err != nil {
+		return fmt.Errorf("excludeRepo: %w", err)
+	}
+	if s.includeTagRe, err = compilePatterns(s.IncludeTag); err != nil {
+		return fmt.Errorf("includeTag: %w", err)
+	}
+	if s.excludeTagRe, err = compilePatterns(s.ExcludeTag); err != nil {
+		return fmt.Errorf("excludeTag: %w", err)
+	}
+	if s.MinSeverity != "" {
+		if _, ok := severityOrder[strings.ToUpper(s.MinSeverity)]; !ok {
+			return fmt.Errorf("unknown minSeverity %q", s.MinSeverity)
+		}
+	}
+	return nil
+}
+
+// mergeLists appends another spec's repeated-value fields onto s, for
+// combining CLI-flag values with a spec loaded from --filter-file rather
+// than letting one silently replace the other.
+func (s *FilterSpec) mergeLists(other *FilterSpec) {
+	s.IncludeRepo = append(s.IncludeRepo, other.IncludeRepo...)
+	s.ExcludeRepo = append(s.ExcludeRepo, other.ExcludeRepo...)
+	s.IncludeTag = append(s.IncludeTag, other.IncludeTag...)
+	s.ExcludeTag = append(s.ExcludeTag, other.ExcludeTag...)
+	s.IncludeCVE = append(s.IncludeCVE, other.IncludeCVE...)
+	s.ExcludeCVE = append(s.ExcludeCVE, other.ExcludeCVE...)
+	s.ExcludeInstance = append(s.ExcludeInstance, other.ExcludeInstance...)
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// toFilterCriteria translates the parts of the spec the Inspector2 API can
+// evaluate server-side into a types.FilterCriteria. Regex include/exclude
+// patterns cannot be expressed this way (StringFilter only supports EQUALS,
+// PREFIX and NOT_EQUALS) so those are left for postFilter.
+func (s *FilterSpec) toFilterCriteria() *types.FilterCriteria {
+	criteria := &types.FilterCriteria{}
+	for _, cve := range s.IncludeCVE {
+		criteria.VulnerabilityId = append(criteria.VulnerabilityId, types.StringFilter{
+			Comparison: types.StringComparisonEquals,
+			Value:      stringPtr(cve),
+		})
+	}
+	for _, cve := range s.ExcludeCVE {
+		criteria.VulnerabilityId = append(criteria.VulnerabilityId, types.StringFilter{
+			Comparison: types.StringComparisonNotEquals,
+			Value:      stringPtr(cve),
+		})
+	}
+	if s.FixAvailable != nil {
+		value := "NO"
+		if *s.FixAvailable {
+			value = "YES"
+		}
+		criteria.FixAvailable = []types.StringFilter{{
+			Comparison: types.StringComparisonEquals,
+			Value:      stringPtr(value),
+		}}
+	}
+	if s.MinSeverity != "" {
+		for sev, rank := range severityOrder {
+			if rank >= severityOrder[strings.ToUpper(s.MinSeverity)] {
+				criteria.Severity = append(criteria.Severity, types.StringFilter{
+					Comparison: types.StringComparisonEquals,
+					Value:      stringPtr(sev),
+				})
+			}
+		}
+	}
+	return criteria
+}
+
+// postFilter applies everything toFilterCriteria could not push down to the
+// API: repo/tag regex include-exclude, and the exclude-instance ARN list.
+func (s *FilterSpec) postFilter(findings []types.Finding) []types.Finding {
+	excludeInstance := make(map[string]bool, len(s.ExcludeInstance))
+	for _, arn := range s.ExcludeInstance {
+		excludeInstance[arn] = true
+	}
+
+	filtered := make([]types.Finding, 0, len(findings))
+	for _, f := range findings {
+		if f.FindingArn != nil && excludeInstance[*f.FindingArn] {
+			continue
+		}
+		repo := extractRepo(f)
+		if !matchIncludeExclude(repo, s.includeRepoRe, s.excludeRepoRe) {
+			continue
+		}
+		tag := extractTag(f)
+		if !matchIncludeExclude(tag, s.includeTagRe, s.excludeTagRe) {
+			continue
+		}
+		filtered = append(filtered, f)
+	}
+	return filtered
+}
+
+// matchIncludeExclude returns true when value passes the include list (or
+// the include list is empty) and fails every pattern in the exclude list.
+func matchIncludeExclude(value string, include, exclude []*regexp.Regexp) bool {
+	if len(include) > 0 {
+		matched := false
+		for _, re := range include {
+			if re.MatchString(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, re := range exclude {
+		if re.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func extractTag(f types.Finding) string {
+	if len(f.Resources) != 1 || f.Resources[0].Details.AwsEcrContainerImage == nil {
+		return ""
+	}
+	tags := f.Resources[0].Details.AwsEcrContainerImage.ImageTags
+	if len(tags) == 0 {
+		return ""
+	}
+	return tags[0]
+}
+
+// repeatedFlag implements flag.Value to collect a flag that may be passed
+// multiple times, e.g. --include-repo=foo --include-repo=bar.
+type repeatedFlag struct {
+	values *[]string
+}
+
+func (r repeatedFlag) String() string {
+	if r.values == nil {
+		return ""
+	}
+	return strings.Join(*r.values, ",")
+}
+
+func (r repeatedFlag) Set(value string) error {
+	*r.values = append(*r.values, value)
+	return nil
+}
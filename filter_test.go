@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/inspector2/types"
+)
+
+func TestMatchIncludeExclude(t *testing.T) {
+	include, err := compilePatterns([]string{"^prod-.*"})
+	if err != nil {
+		t.Fatalf("compilePatterns: %v", err)
+	}
+	exclude, err := compilePatterns([]string{"-old$"})
+	if err != nil {
+		t.Fatalf("compilePatterns: %v", err)
+	}
+
+	cases := []struct {
+		value string
+		want  bool
+	}{
+		{"prod-api", true},
+		{"prod-api-old", false},
+		{"staging-api", false},
+	}
+	for _, c := range cases {
+		if got := matchIncludeExclude(c.value, include, exclude); got != c.want {
+			t.Errorf("matchIncludeExclude(%q) = %v, want %v", c.value, got, c.want)
+		}
+	}
+}
+
+func TestMatchIncludeExcludeNoInclude(t *testing.T) {
+	exclude, err := compilePatterns([]string{"^deny"})
+	if err != nil {
+		t.Fatalf("compilePatterns: %v", err)
+	}
+	if !matchIncludeExclude("anything", nil, exclude) {
+		t.Error("expected a value to pass when no include patterns are set")
+	}
+	if matchIncludeExclude("denylisted", nil, exclude) {
+		t.Error("expected the exclude pattern to still apply")
+	}
+}
+
+func TestFilterSpecPostFilter(t *testing.T) {
+	spec := &FilterSpec{
+		IncludeRepo:     []string{"^keep$"},
+		ExcludeInstance: []string{"arn:suppressed"},
+	}
+	if err := spec.compile(); err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	findings := []types.Finding{
+		finding("keep", "arn:kept"),
+		finding("drop", "arn:dropped"),
+		finding("keep", "arn:suppressed"),
+	}
+
+	got := spec.postFilter(findings)
+	if len(got) != 1 {
+		t.Fatalf("expected exactly one finding to survive postFilter, got %d", len(got))
+	}
+	if got[0].FindingArn == nil || *got[0].FindingArn != "arn:kept" {
+		t.Errorf("expected the surviving finding to be arn:kept, got %v", got[0].FindingArn)
+	}
+}
+
+func finding(repo, arn string) types.Finding {
+	return types.Finding{
+		FindingArn: stringPtr(arn),
+		Resources: []types.Resource{{
+			Details: &types.ResourceDetails{
+				AwsEcrContainerImage: &types.AwsEcrContainerImageDetails{
+					RepositoryName: stringPtr(repo),
+				},
+			},
+		}},
+	}
+}
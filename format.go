@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/inspector2/types"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+)
+
+// FindingRecord is the flattened, per-finding view used by every Formatter
+// except "table" (which renders the aggregated summary instead). Keeping
+// this separate from types.Finding means formatters don't need to know
+// anything about the Inspector2 API shape. ResourceARN holds the finding's
+// own ARN (Finding.FindingArn); the ARN of the scanned image resource is not
+// recorded.
+type FindingRecord struct {
+	Account         string  `json:"account,omitempty" yaml:"account,omitempty"`
+	Region          string  `json:"region,omitempty" yaml:"region,omitempty"`
+	Repository      string  `json:"repository" yaml:"repository"`
+	Tag             string  `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Severity        string  `json:"severity" yaml:"severity"`
+	CVEID           string  `json:"cveId,omitempty" yaml:"cveId,omitempty"`
+	PackageName     string  `json:"packageName,omitempty" yaml:"packageName,omitempty"`
+	PackageVersion  string  `json:"packageVersion,omitempty" yaml:"packageVersion,omitempty"`
+	FixedVersion    string  `json:"fixedVersion,omitempty" yaml:"fixedVersion,omitempty"`
+	CVSSScore       float64 `json:"cvssScore,omitempty" yaml:"cvssScore,omitempty"`
+	ResourceARN     string  `json:"resourceArn,omitempty" yaml:"resourceArn,omitempty"`
+	EPSSScore       float64 `json:"epssScore,omitempty" yaml:"epssScore,omitempty"`
+	ExploitObserved bool    `json:"exploitObserved,omitempty" yaml:"exploitObserved,omitempty"`
+	CisaKev         bool    `json:"cisaKev,omitempty" yaml:"cisaKev,omitempty"`
+}
+
+// toRecords flattens findings into FindingRecords, one per vulnerable
+// package so a finding affecting several packages is not collapsed into a
+// single row.
+func toRecords(findings []types.Finding) []FindingRecord {
+	var records []FindingRecord
+	for _, f := range findings {
+		base := FindingRecord{
+			Repository: extractRepo(f),
+			Tag:        extractTag(f),
+			Severity:   string(f.Severity),
+		}
+		if f.FindingArn != nil {
+			base.ResourceARN = *f.FindingArn
+		}
+		details := f.PackageVulnerabilityDetails
+		if details == nil || len(details.VulnerablePackages) == 0 {
+			records = append(records, base)
+			continue
+		}
+		if details.VulnerabilityId != nil {
+			base.CVEID = *details.VulnerabilityId
+		}
+		base.CVSSScore = highestCvssScore(details)
+		for _, pkg := range details.VulnerablePackages {
+			record := base
+			record.PackageName = aws.ToString(pkg.Name)
+			record.PackageVersion = aws.ToString(pkg.Version)
+			record.FixedVersion = aws.ToString(pkg.FixedInVersion)
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// highestCvssScore returns the highest base score reported across all CVSS
+// sources on the finding, falling back to 0 when none are present.
+func highestCvssScore(details *types.PackageVulnerabilityDetails) float64 {
+	var highest float64
+	for _, c := range details.Cvss {
+		if c.BaseScore != nil && *c.BaseScore > highest {
+			highest = *c.BaseScore
+		}
+	}
+	return highest
+}
+
+// applyEnrichment stamps EPSS/exploit/KEV data from BatchGetFindingDetails
+// onto every record sharing that finding's ARN.
+func applyEnrichment(records []FindingRecord, details map[string]findingDetail) {
+	for i, r := range records {
+		detail, ok := details[r.ResourceARN]
+		if !ok {
+			continue
+		}
+		records[i].EPSSScore = detail.EPSSScore
+		records[i].ExploitObserved = detail.ExploitObserved
+		records[i].CisaKev = detail.CisaKev
+	}
+}
+
+// Formatter renders a set of findings to a writer. Implementations are
+// selected by the -o/--output flag.
+type Formatter interface {
+	Format(w io.Writer, records []FindingRecord) error
+}
+
+// newFormatter resolves the -o/--output flag value to a Formatter; an empty
+// value selects the table.
+func newFormatter(name, tmpl string) (Formatter, error) {
+	switch name {
+	case "", "table":
+		return tableFormatter{}, nil
+	case "json":
+		return jsonFormatter{}, nil
+	case "yaml":
+		return yamlFormatter{}, nil
+	case "csv":
+		return csvFormatter{}, nil
+	case "sarif":
+		return sarifFormatter{}, nil
+	case "template":
+		if tmpl == "" {
+			return nil, fmt.Errorf("--template is required when --output=template")
+		}
+		t, err := template.New("finding").Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("parsing --template: %w", err)
+		}
+		return templateFormatter{tmpl: t}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", name)
+	}
+}
+
+// tableFormatter re-aggregates records into the repository/severity summary
+// table this tool has always printed.
+type tableFormatter struct{}
+
+// summaryKey groups records by account, region and repository so a
+// multi-account/multi-region scan renders one row per combination instead
+// of merging unrelated repositories together.
+type summaryKey struct {
+	account string
+	region  string
+	repo    string
+}
+
+func (tableFormatter) Format(w io.Writer, records []FindingRecord) error {
+	summaryTable := make(map[summaryKey]summary)
+	tagByKey := make(map[summaryKey]string)
+	var totals summary
+	counted := make(map[string]bool)
+	for _, r := range records {
+		if r.Repository == "" {
+			continue
+		}
+		// toRecords emits one record per vulnerable package; count each
+		// finding once.
+		if r.ResourceARN != "" {
+			if counted[r.ResourceARN] {
+				continue
+			}
+			counted[r.ResourceARN] = true
+		}
+		key := summaryKey{account: r.Account, region: r.Region, repo: r.Repository}
+		s := summaryTable[key]
+		switch types.Severity(r.Severity) {
+		case types.SeverityCritical:
+			s.critical++
+			totals.critical++
+		case types.SeverityHigh:
+			s.high++
+			totals.high++
+		case types.SeverityMedium:
+			s.medium++
+			totals.medium++
+		case types.SeverityLow:
+			s.low++
+			totals.low++
+		}
+		summaryTable[key] = s
+		if tagByKey[key] == "" {
+			tagByKey[key] = r.Tag
+		}
+	}
+
+	keys := make([]summaryKey, 0, len(summaryTable))
+	for k := range summaryTable {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].account != keys[j].account {
+			return keys[i].account < keys[j].account
+		}
+		if keys[i].region != keys[j].region {
+			return keys[i].region < keys[j].region
+		}
+		return keys[i].repo < keys[j].repo
+	})
+
+	table := tablewriter.NewWriter(w)
+	table.Header([]string{"Account", "Region", "Repository", "Tag", "Critical", "High", "Medium", "Low", "Total"})
+	for _, k := range keys {
+		s := summaryTable[k]
+		table.Append([]any{k.account, k.region, k.repo, tagByKey[k], s.critical, s.high, s.medium, s.low, s.total()})
+	}
+	table.Footer("", "", "Total", "", totals.critical, totals.high, totals.medium, totals.low, totals.total())
+	return table.Render()
+}
+
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(w io.Writer, records []FindingRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+type yamlFormatter struct{}
+
+func (yamlFormatter) Format(w io.Writer, records []FindingRecord) error {
+	enc := yaml.NewEncoder(w)
+	defer enc.Close()
+	return enc.Encode(records)
+}
+
+type csvFormatter struct{}
+
+func (csvFormatter) Format(w io.Writer, records []FindingRecord) error {
+	writer := csv.NewWriter(w)
+	header := []string{
+		"Account", "Region", "Repository", "Tag", "Severity", "CVEID",
+		"PackageName", "PackageVersion", "FixedVersion", "CVSSScore", "ResourceARN",
+		"EPSSScore", "ExploitObserved", "CisaKev",
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, r := range records {
+		row := []string{
+			r.Account, r.Region, r.Repository, r.Tag, r.Severity, r.CVEID,
+			r.PackageName, r.PackageVersion, r.FixedVersion,
+			fmt.Sprintf("%g", r.CVSSScore), r.ResourceARN,
+			fmt.Sprintf("%g", r.EPSSScore), strconv.FormatBool(r.ExploitObserved), strconv.FormatBool(r.CisaKev),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// templateFormatter renders each finding through a user-supplied
+// text/template, e.g. --template='{{.Repository}} {{.Severity}}'.
+type templateFormatter struct {
+	tmpl *template.Template
+}
+
+func (f templateFormatter) Format(w io.Writer, records []FindingRecord) error {
+	for _, r := range records {
+		if err := f.tmpl.Execute(w, r); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sarifFormatter emits a minimal SARIF 2.1.0 log so findings can be piped
+// into GitHub code-scanning or other SARIF-aware tooling.
+type sarifFormatter struct{}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+func (sarifFormatter) Format(w io.Writer, records []FindingRecord) error {
+	ruleSeen := make(map[string]bool)
+	log := sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{Name: "inspector"}},
+		}},
+	}
+	for _, r := range records {
+		ruleID := r.CVEID
+		if ruleID == "" {
+			ruleID = "UNKNOWN"
+		}
+		if !ruleSeen[ruleID] {
+			log.Runs[0].Tool.Driver.Rules = append(log.Runs[0].Tool.Driver.Rules, sarifRule{ID: ruleID})
+			ruleSeen[ruleID] = true
+		}
+		log.Runs[0].Results = append(log.Runs[0].Results, sarifResult{
+			RuleID: ruleID,
+			Level:  sarifLevel(r.Severity),
+			Message: sarifMessage{
+				Text: fmt.Sprintf("%s affects %s %s (fixed in %s)", ruleID, r.PackageName, r.PackageVersion, r.FixedVersion),
+			},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: r.Repository},
+				},
+			}},
+		})
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(log); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func sarifLevel(severity string) string {
+	switch types.Severity(severity) {
+	case types.SeverityCritical, types.SeverityHigh:
+		return "error"
+	case types.SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
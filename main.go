@@ -6,72 +6,37 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"sort"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/inspector2"
 	"github.com/aws/aws-sdk-go-v2/service/inspector2/types"
-	"github.com/aws/aws-sdk-go-v2/service/sts"
-	"github.com/olekukonko/tablewriter"
 )
 
-func checkCallerIdentity(ctx context.Context, config aws.Config) error {
-	client := sts.NewFromConfig(config)
-	identity, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
-	if err != nil {
-		return err
-	}
-	fmt.Printf("Account: %s, Arn: %s\n", aws.ToString(identity.Account), aws.ToString(identity.Arn))
-	return nil
-}
-
 func stringPtr(s string) *string {
 	return &s
 }
 
-func fetchFindings(ctx context.Context, config aws.Config, tag *string, ignore *string) ([]types.Finding, error) {
-	filerCriteria :=
-		&types.FilterCriteria{
-			EcrImageTags: []types.StringFilter{{
-				Comparison: types.StringComparisonEquals,
-				Value:      tag,
-			}},
-		}
-	if ignore != nil && *ignore != "" {
-		ignoredRepos := strings.SplitSeq(*ignore, ",")
-		for r := range ignoredRepos {
-			filerCriteria.EcrImageRepositoryName = append(filerCriteria.EcrImageRepositoryName,
-				types.StringFilter{
-					Comparison: types.StringComparisonNotEquals,
-					Value:      stringPtr(r),
-				},
-			)
-		}
-	}
+func fetchFindings(ctx context.Context, config aws.Config, spec *FilterSpec) ([]types.Finding, error) {
 	client := inspector2.NewFromConfig(config)
-	fmt.Println("Getting findings ...")
-	listResult, err := client.ListFindings(ctx, &inspector2.ListFindingsInput{
-		FilterCriteria: filerCriteria,
+	paginator := inspector2.NewListFindingsPaginator(client, &inspector2.ListFindingsInput{
+		FilterCriteria: spec.toFilterCriteria(),
 	})
-	if err != nil {
-		return nil, err
-	}
-	findings := listResult.Findings
-	for listResult.NextToken != nil {
-		fmt.Println("Getting further findings ...")
-		listResult, err = client.ListFindings(ctx, &inspector2.ListFindingsInput{
-			// Resubmit filer criteria otherwise a validation error occurs
-			FilterCriteria: filerCriteria,
-			NextToken:      listResult.NextToken,
-		})
+
+	var findings []types.Finding
+	for paginator.HasMorePages() {
+		fmt.Fprintln(os.Stderr, "Getting findings ...")
+		page, err := paginator.NextPage(ctx)
 		if err != nil {
 			return findings, err
 		}
-		findings = append(findings, listResult.Findings...)
+		findings = append(findings, page.Findings...)
 	}
-	return findings, err
+	return spec.postFilter(findings), nil
 }
 
 func extractRepo(f types.Finding) string {
@@ -103,80 +68,170 @@ func (s summary) total() int {
 }
 
 func main() {
+	if len(os.Args) > 1 && (os.Args[1] == "prune" || os.Args[1] == "quarantine") {
+		runLifecycleCommand(os.Args[1], os.Args[2:])
+		return
+	}
+
 	ctx := context.Background()
 
 	// Program flags
-	profile := flag.String("p", "", "Name of AWS profile")
-	tag := flag.String("t", "", "Image tag used for filtering")
-	ignore := flag.String("i", "", "Repositories to ignore")
+	profile := flag.String("p", "", "Name of AWS profile (legacy alias for -profiles with a single profile)")
+	profiles := flag.String("profiles", "", "Comma-separated AWS profile names to scan")
+	regions := flag.String("regions", "", "Comma-separated AWS regions to scan (defaults to each profile's own region)")
+	allRegionsFlag := flag.Bool("all-regions", false, "Scan every region available to the account, discovered via EC2 DescribeRegions")
+	maxConcurrency := flag.Int("max-concurrency", defaultMaxConcurrency, "Maximum number of profile/region scans to run concurrently")
+	filterFile := flag.String("filter-file", "", "Path to a YAML or JSON file containing a filter specification")
+	minSeverity := flag.String("min-severity", "", "Only report findings at or above this severity, e.g. HIGH")
+	fixAvailable := flag.String("fix-available", "", "Only report findings with a fix available: true or false")
+	output := flag.String("o", "table", "Output format: table, json, yaml, csv, sarif or template")
+	flag.StringVar(output, "output", "table", "Output format: table, json, yaml, csv, sarif or template")
+	tmpl := flag.String("template", "", "Go text/template body used when --output=template, e.g. '{{.Repository}} {{.Severity}}'")
+	mode := flag.String("mode", "findings", "findings (default) or coverage, which additionally lists unscanned ECR repositories")
+	enrich := flag.Bool("enrich", false, "Enrich findings with EPSS score, exploit observation and CISA KEV status via BatchGetFindingDetails")
+	snapshotPath := flag.String("snapshot", "", "Path to a JSON snapshot file; diffs the current findings against it as new/resolved/still-open")
+	failOn := flag.String("fail-on", "", "Comma-separated severities (e.g. critical,high) that make the tool exit non-zero when new findings of that severity appear")
+
+	spec := &FilterSpec{}
+	var tagGlobs []string
+	flag.Var(repeatedFlag{&tagGlobs}, "t", "Image tag glob to include (repeatable); matches ECR image tags")
+	flag.Var(repeatedFlag{&spec.IncludeRepo}, "include-repo", "Regex a repository name must match to be included (repeatable)")
+	flag.Var(repeatedFlag{&spec.ExcludeRepo}, "exclude-repo", "Regex a repository name must not match (repeatable)")
+	flag.Var(repeatedFlag{&spec.IncludeTag}, "include-tag", "Regex an image tag must match to be included (repeatable)")
+	flag.Var(repeatedFlag{&spec.ExcludeTag}, "exclude-tag", "Regex an image tag must not match (repeatable)")
+	flag.Var(repeatedFlag{&spec.IncludeCVE}, "include-cve", "CVE ID to include (repeatable)")
+	flag.Var(repeatedFlag{&spec.ExcludeCVE}, "exclude-cve", "CVE ID to exclude (repeatable)")
+	flag.Var(repeatedFlag{&spec.ExcludeInstance}, "exclude-instance", "Finding ARN to suppress (repeatable)")
 	flag.Parse()
 
-	if *profile == "" {
-		log.Fatal("Please provide a AWS profile name")
+	// -t takes shell-style globs (* and ?); each becomes an anchored regex so
+	// it is matched alongside the -include-tag patterns.
+	for _, g := range tagGlobs {
+		re := strings.ReplaceAll(regexp.QuoteMeta(g), `\*`, ".*")
+		re = strings.ReplaceAll(re, `\?`, ".")
+		spec.IncludeTag = append(spec.IncludeTag, "^"+re+"$")
 	}
-	if *tag == "" {
-		log.Fatal("Please provide an image tag")
+
+	profileNames := splitNonEmpty(*profiles)
+	if len(profileNames) == 0 && *profile != "" {
+		profileNames = []string{*profile}
+	}
+	if len(profileNames) == 0 {
+		log.Fatal("Please provide an AWS profile name via -p or -profiles")
 	}
 
-	// Create AWS config
-	config, err := config.LoadDefaultConfig(ctx, config.WithSharedConfigProfile(*profile))
-	if err != nil {
+	if *filterFile != "" {
+		loaded, err := loadFilterFile(*filterFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		loaded.mergeLists(spec)
+		spec = loaded
+	}
+	if *minSeverity != "" {
+		spec.MinSeverity = *minSeverity
+	}
+	if *fixAvailable != "" {
+		fixAvailableBool, err := strconv.ParseBool(*fixAvailable)
+		if err != nil {
+			log.Fatalf("invalid -fix-available value %q: %v", *fixAvailable, err)
+		}
+		spec.FixAvailable = &fixAvailableBool
+	}
+	if err := spec.compile(); err != nil {
 		log.Fatal(err)
 	}
-
-	err = checkCallerIdentity(ctx, config)
+	formatter, err := newFormatter(*output, *tmpl)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if *mode != "findings" && *mode != "coverage" {
+		log.Fatalf("unknown --mode %q", *mode)
+	}
+
+	regionNames := splitNonEmpty(*regions)
+
+	var targets []scanTarget
+	for _, p := range profileNames {
+		profileRegions := regionNames
+		if *allRegionsFlag {
+			cfg, err := config.LoadDefaultConfig(ctx, config.WithSharedConfigProfile(p))
+			if err != nil {
+				log.Fatal(err)
+			}
+			profileRegions, err = allRegions(ctx, cfg)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		if len(profileRegions) == 0 {
+			targets = append(targets, scanTarget{Profile: p})
+			continue
+		}
+		for _, r := range profileRegions {
+			targets = append(targets, scanTarget{Profile: p, Region: r})
+		}
+	}
 
 	// Fetch findings
-	findings, err := fetchFindings(ctx, config, tag, ignore)
-	if err != nil {
+	records, scanErrs := scanAccounts(ctx, targets, spec, *maxConcurrency, *enrich)
+
+	if err := formatter.Format(os.Stdout, records); err != nil {
 		log.Fatal(err)
 	}
 
-	// Summary Table
-	summaryTable := make(map[string]summary)
-	var totals summary
-	for _, f := range findings {
-		name := extractRepo(f)
-		if name == "" {
-			continue
+	if *mode == "coverage" {
+		gaps, gapErrs := scanCoverageGaps(ctx, targets)
+		if err := renderCoverageGaps(os.Stdout, gaps); err != nil {
+			log.Fatal(err)
+		}
+		scanErrs = append(scanErrs, gapErrs...)
+	}
+
+	exitCode := 0
+	if *snapshotPath != "" {
+		previous, err := loadSnapshot(*snapshotPath)
+		if err != nil {
+			log.Fatal(err)
 		}
-		s := summaryTable[name]
-		switch f.Severity {
-		case types.SeverityCritical:
-			s.critical += 1
-			totals.critical += 1
-		case types.SeverityHigh:
-			s.high += 1
-			totals.high += 1
-		case types.SeverityMedium:
-			s.medium += 1
-			totals.medium += 1
-		case types.SeverityLow:
-			s.low += 1
-			totals.low += 1
+		current := buildSnapshot(records, time.Now(), previous)
+		if previous == nil {
+			fmt.Printf("\nNo previous snapshot found at %s; saving baseline of %d findings.\n", *snapshotPath, len(current.Findings))
+		} else {
+			diff := diffSnapshots(previous, current)
+			if err := renderSnapshotDiff(os.Stdout, diff); err != nil {
+				log.Fatal(err)
+			}
+			if failOnSeverities(diff, splitNonEmpty(*failOn)) {
+				exitCode = 1
+			}
+		}
+		if err := saveSnapshot(*snapshotPath, current); err != nil {
+			log.Fatal(err)
 		}
-		summaryTable[name] = s
 	}
 
-	// Get all repo names
-	var repoNames []string
-	for k := range summaryTable {
-		repoNames = append(repoNames, k)
+	if len(scanErrs) > 0 {
+		fmt.Fprintln(os.Stderr, "\nErrors:")
+		for _, e := range scanErrs {
+			fmt.Fprintf(os.Stderr, "  profile=%s region=%s: %v\n", e.Target.Profile, e.Target.Region, e.Err)
+		}
 	}
-	// Sort keys
-	sort.Strings(repoNames)
 
-	// Create and render
-	table := tablewriter.NewWriter(os.Stdout)
-	table.Header([]string{"Repository", "Tag", "Critical", "High", "Medium", "Low", "Total"})
-	for _, n := range repoNames {
-		s := summaryTable[n]
-		table.Append([]any{n, *tag, s.critical, s.high, s.medium, s.low, s.total()})
+	if exitCode != 0 {
+		os.Exit(exitCode)
 	}
-	table.Footer("Total", "", totals.critical, totals.high, totals.medium, totals.low, totals.total())
+}
 
-	table.Render()
+// splitNonEmpty splits a comma-separated flag value, discarding empty
+// elements so a trailing comma or empty string doesn't produce blank
+// entries.
+func splitNonEmpty(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
 }
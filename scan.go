@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// defaultMaxConcurrency bounds how many account/region scans run at once,
+// matching the worker pool size used elsewhere for AWS API fan-out.
+const defaultMaxConcurrency = 10
+
+// scanTarget identifies a single profile/region combination to scan. Region
+// is empty when the profile's own default region should be used.
+type scanTarget struct {
+	Profile string
+	Region  string
+}
+
+// accountError records a scan failure against the target that produced it,
+// so one failing account/region can be reported without aborting the rest
+// of the scan.
+type accountError struct {
+	Target scanTarget
+	Err    error
+}
+
+// scanAccounts fans fetchFindings out across targets using a bounded worker
+// pool, merging every account/region's findings into a single slice of
+// records stamped with their Account and Region. A failure against one
+// target is collected in the returned errors rather than aborting the scan.
+func scanAccounts(ctx context.Context, targets []scanTarget, spec *FilterSpec, maxConcurrency int, enrich bool) ([]FindingRecord, []accountError) {
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultMaxConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		records []FindingRecord
+		errs    []accountError
+		wg      sync.WaitGroup
+	)
+	sem := make(chan struct{}, maxConcurrency)
+
+	for _, target := range targets {
+		target := target
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result, err := scanOne(ctx, target, spec, enrich)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, accountError{Target: target, Err: err})
+				return
+			}
+			records = append(records, result...)
+		}()
+	}
+	wg.Wait()
+	return records, errs
+}
+
+// scanOne scans a single profile/region combination and stamps the
+// resulting records with the account ID and region they came from.
+func scanOne(ctx context.Context, target scanTarget, spec *FilterSpec, enrich bool) ([]FindingRecord, error) {
+	cfg, err := loadConfig(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s region %s: %w", target.Profile, target.Region, err)
+	}
+
+	account, err := accountID(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s region %s: %w", target.Profile, target.Region, err)
+	}
+
+	findings, err := fetchFindings(ctx, cfg, spec)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s region %s: %w", target.Profile, target.Region, err)
+	}
+
+	records := toRecords(findings)
+	if enrich {
+		details, err := enrichFindingDetails(ctx, cfg, findings)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s region %s: enriching findings: %w", target.Profile, target.Region, err)
+		}
+		applyEnrichment(records, details)
+	}
+	for i := range records {
+		records[i].Account = account
+		records[i].Region = cfg.Region
+	}
+	return records, nil
+}
+
+func loadConfig(ctx context.Context, target scanTarget) (aws.Config, error) {
+	opts := []func(*config.LoadOptions) error{config.WithSharedConfigProfile(target.Profile)}
+	if target.Region != "" {
+		opts = append(opts, config.WithRegion(target.Region))
+	}
+	return config.LoadDefaultConfig(ctx, opts...)
+}
+
+func accountID(ctx context.Context, cfg aws.Config) (string, error) {
+	client := sts.NewFromConfig(cfg)
+	identity, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(os.Stderr, "Account: %s, Arn: %s\n", aws.ToString(identity.Account), aws.ToString(identity.Arn))
+	return aws.ToString(identity.Account), nil
+}
+
+// allRegions enumerates every region available to the account via EC2
+// DescribeRegions, backing the --all-regions flag.
+func allRegions(ctx context.Context, cfg aws.Config) ([]string, error) {
+	client := ec2.NewFromConfig(cfg)
+	out, err := client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, err
+	}
+	var regions []string
+	for _, r := range out.Regions {
+		regions = append(regions, aws.ToString(r.RegionName))
+	}
+	return regions, nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// snapshotSchemaVersion is bumped whenever the on-disk Snapshot shape
+// changes, so older snapshot files can be migrated or rejected explicitly
+// rather than silently misread.
+const snapshotSchemaVersion = 1
+
+// SnapshotFinding is the durable, per-finding record persisted across runs
+// by --snapshot so a regression gate can tell new findings from ones
+// already known about.
+type SnapshotFinding struct {
+	ARN        string    `json:"arn"`
+	Severity   string    `json:"severity"`
+	CVEID      string    `json:"cveId,omitempty"`
+	Repository string    `json:"repository"`
+	Tag        string    `json:"tag,omitempty"`
+	FirstSeen  time.Time `json:"firstSeen"`
+}
+
+// Snapshot is the versioned JSON document written to the --snapshot path.
+type Snapshot struct {
+	Version  int               `json:"version"`
+	Findings []SnapshotFinding `json:"findings"`
+}
+
+// loadSnapshot reads a Snapshot from disk, returning (nil, nil) when the
+// file does not yet exist so the caller can treat this as a first run.
+func loadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snap := &Snapshot{}
+	if err := json.Unmarshal(data, snap); err != nil {
+		return nil, fmt.Errorf("parsing snapshot %s: %w", path, err)
+	}
+	if snap.Version != snapshotSchemaVersion {
+		return nil, fmt.Errorf("snapshot %s has schema version %d, expected %d", path, snap.Version, snapshotSchemaVersion)
+	}
+	return snap, nil
+}
+
+func saveSnapshot(path string, snap *Snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// buildSnapshot turns the current records into a Snapshot, carrying forward
+// FirstSeen from the previous snapshot for findings that are still open.
+func buildSnapshot(records []FindingRecord, now time.Time, previous *Snapshot) *Snapshot {
+	firstSeen := make(map[string]time.Time)
+	if previous != nil {
+		for _, f := range previous.Findings {
+			firstSeen[f.ARN] = f.FirstSeen
+		}
+	}
+
+	seen := make(map[string]bool)
+	snap := &Snapshot{Version: snapshotSchemaVersion}
+	for _, r := range records {
+		if r.ResourceARN == "" || seen[r.ResourceARN] {
+			continue
+		}
+		seen[r.ResourceARN] = true
+		fs, ok := firstSeen[r.ResourceARN]
+		if !ok {
+			fs = now
+		}
+		snap.Findings = append(snap.Findings, SnapshotFinding{
+			ARN:        r.ResourceARN,
+			Severity:   r.Severity,
+			CVEID:      r.CVEID,
+			Repository: r.Repository,
+			Tag:        r.Tag,
+			FirstSeen:  fs,
+		})
+	}
+	return snap
+}
+
+// snapshotDiff is the result of comparing a previous snapshot against the
+// current one.
+type snapshotDiff struct {
+	New       []SnapshotFinding
+	Resolved  []SnapshotFinding
+	StillOpen []SnapshotFinding
+}
+
+func diffSnapshots(previous, current *Snapshot) snapshotDiff {
+	prevByArn := make(map[string]SnapshotFinding)
+	if previous != nil {
+		for _, f := range previous.Findings {
+			prevByArn[f.ARN] = f
+		}
+	}
+	currByArn := make(map[string]SnapshotFinding)
+	for _, f := range current.Findings {
+		currByArn[f.ARN] = f
+	}
+
+	var diff snapshotDiff
+	for arn, f := range currByArn {
+		if _, ok := prevByArn[arn]; ok {
+			diff.StillOpen = append(diff.StillOpen, f)
+		} else {
+			diff.New = append(diff.New, f)
+		}
+	}
+	for arn, f := range prevByArn {
+		if _, ok := currByArn[arn]; !ok {
+			diff.Resolved = append(diff.Resolved, f)
+		}
+	}
+	sortFindings(diff.New)
+	sortFindings(diff.Resolved)
+	sortFindings(diff.StillOpen)
+	return diff
+}
+
+func sortFindings(findings []SnapshotFinding) {
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Repository != findings[j].Repository {
+			return findings[i].Repository < findings[j].Repository
+		}
+		return findings[i].ARN < findings[j].ARN
+	})
+}
+
+// renderSnapshotDiff prints the new/resolved/still-open tables plus a net
+// delta per severity per repository, the report a CI regression gate would
+// show in its logs.
+func renderSnapshotDiff(w io.Writer, diff snapshotDiff) error {
+	sections := []struct {
+		title    string
+		findings []SnapshotFinding
+	}{
+		{"New findings", diff.New},
+		{"Resolved findings", diff.Resolved},
+		{"Still-open findings", diff.StillOpen},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		table := tablewriter.NewWriter(w)
+		table.Header([]string{"Repository", "Tag", "Severity", "CVEID", "First Seen"})
+		for _, f := range section.findings {
+			table.Append([]any{f.Repository, f.Tag, f.Severity, f.CVEID, f.FirstSeen.Format(time.RFC3339)})
+		}
+		if err := table.Render(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w, "\nNet delta per severity per repository:")
+	delta := deltaBySeverityAndRepo(diff)
+	var repoNames []string
+	for repo := range delta {
+		repoNames = append(repoNames, repo)
+	}
+	sort.Strings(repoNames)
+
+	deltaTable := tablewriter.NewWriter(w)
+	deltaTable.Header([]string{"Repository", "Critical", "High", "Medium", "Low"})
+	for _, repo := range repoNames {
+		s := delta[repo]
+		deltaTable.Append([]any{repo, formatDelta(s.critical), formatDelta(s.high), formatDelta(s.medium), formatDelta(s.low)})
+	}
+	return deltaTable.Render()
+}
+
+func formatDelta(n int) string {
+	if n > 0 {
+		return fmt.Sprintf("+%d", n)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+func deltaBySeverityAndRepo(diff snapshotDiff) map[string]summary {
+	delta := make(map[string]summary)
+	apply := func(findings []SnapshotFinding, sign int) {
+		for _, f := range findings {
+			s := delta[f.Repository]
+			switch f.Severity {
+			case "CRITICAL":
+				s.critical += sign
+			case "HIGH":
+				s.high += sign
+			case "MEDIUM":
+				s.medium += sign
+			case "LOW":
+				s.low += sign
+			}
+			delta[f.Repository] = s
+		}
+	}
+	apply(diff.New, 1)
+	apply(diff.Resolved, -1)
+	return delta
+}
+
+// failOnSeverities reports whether any new finding matches one of the
+// configured --fail-on severities, turning the snapshot diff into a CI
+// regression gate.
+func failOnSeverities(diff snapshotDiff, failOn []string) bool {
+	thresholds := make(map[string]bool, len(failOn))
+	for _, s := range failOn {
+		thresholds[strings.ToUpper(s)] = true
+	}
+	for _, f := range diff.New {
+		if thresholds[f.Severity] {
+			return true
+		}
+	}
+	return false
+}
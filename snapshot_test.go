@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestDiffSnapshots(t *testing.T) {
+	previous := &Snapshot{Findings: []SnapshotFinding{
+		{ARN: "arn:still-open", Repository: "repo"},
+		{ARN: "arn:resolved", Repository: "repo"},
+	}}
+	current := &Snapshot{Findings: []SnapshotFinding{
+		{ARN: "arn:still-open", Repository: "repo"},
+		{ARN: "arn:new", Repository: "repo"},
+	}}
+
+	diff := diffSnapshots(previous, current)
+
+	if len(diff.New) != 1 || diff.New[0].ARN != "arn:new" {
+		t.Errorf("expected exactly arn:new in diff.New, got %v", diff.New)
+	}
+	if len(diff.Resolved) != 1 || diff.Resolved[0].ARN != "arn:resolved" {
+		t.Errorf("expected exactly arn:resolved in diff.Resolved, got %v", diff.Resolved)
+	}
+	if len(diff.StillOpen) != 1 || diff.StillOpen[0].ARN != "arn:still-open" {
+		t.Errorf("expected exactly arn:still-open in diff.StillOpen, got %v", diff.StillOpen)
+	}
+}
+
+func TestDiffSnapshotsFirstRun(t *testing.T) {
+	current := &Snapshot{Findings: []SnapshotFinding{{ARN: "arn:new", Repository: "repo"}}}
+	diff := diffSnapshots(nil, current)
+	if len(diff.New) != 1 || len(diff.Resolved) != 0 || len(diff.StillOpen) != 0 {
+		t.Errorf("expected every finding to be New on a first run, got %+v", diff)
+	}
+}
+
+func TestFailOnSeverities(t *testing.T) {
+	diff := snapshotDiff{New: []SnapshotFinding{{Severity: "HIGH"}}}
+
+	if !failOnSeverities(diff, []string{"critical", "high"}) {
+		t.Error("expected a HIGH new finding to trip -fail-on=critical,high")
+	}
+	if failOnSeverities(diff, []string{"critical"}) {
+		t.Error("did not expect a HIGH new finding to trip -fail-on=critical")
+	}
+	if failOnSeverities(snapshotDiff{StillOpen: []SnapshotFinding{{Severity: "CRITICAL"}}}, []string{"critical"}) {
+		t.Error("failOnSeverities must only look at new findings, not still-open ones")
+	}
+}